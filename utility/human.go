@@ -0,0 +1,176 @@
+package utility
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs exposes the human formatters to WriteGoTemplate and
+// WriteCustomOutput, so format strings can write e.g.
+// "{{humanBytes .Size}}" or "{{humanTime .CreatedAt}}". humanBytes and
+// humanCount go through the "Any" wrappers since template values stored
+// via AppendDataAny carry the named Bytes/Count types, not a plain int64.
+var templateFuncs = template.FuncMap{
+	"humanBytes":    humanBytesAny,
+	"humanCount":    humanCountAny,
+	"humanDuration": humanDuration,
+	"humanTime":     humanTime,
+}
+
+// Formatter renders a value as a human-friendly string for table and
+// key-value output. Register one per type with RegisterFormatter.
+type Formatter func(interface{}) string
+
+var formatters = map[reflect.Type]Formatter{}
+
+func init() {
+	RegisterFormatter(time.Time{}, func(v interface{}) string {
+		return v.(time.Time).Format(time.RFC3339)
+	})
+	RegisterFormatter(time.Duration(0), func(v interface{}) string {
+		return humanDuration(v.(time.Duration))
+	})
+	RegisterFormatter(Bytes(0), humanBytesAny)
+	RegisterFormatter(Count(0), humanCountAny)
+}
+
+// RegisterFormatter installs the Formatter used to render values sharing
+// sample's type in table/key-value output. Built-in defaults cover
+// time.Time, time.Duration, Bytes and Count; call this to override them or
+// add support for another type.
+func RegisterFormatter(sample interface{}, f Formatter) {
+	formatters[reflect.TypeOf(sample)] = f
+}
+
+// formatValue renders value for table/key-value display, using a
+// registered Formatter when one matches its type and falling back to
+// fmt.Sprint otherwise
+func formatValue(value interface{}) string {
+	if f, found := formatters[reflect.TypeOf(value)]; found {
+		return f(value)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Bytes is a byte count. In table/key-value output it renders as a
+// human-friendly size (e.g. "1.3 GiB"); in JSON/YAML output it marshals as
+// a plain number.
+type Bytes int64
+
+// Count is an integer that is expected to often be large (e.g. request
+// counts). In table/key-value output it renders with a k/M/B suffix (e.g.
+// "12.4k"); in JSON/YAML output it marshals as a plain number.
+type Count int64
+
+// humanBytes renders n bytes as a short, human-friendly size using binary
+// (1024-based) units, e.g. humanBytes(1363148) == "1.3 MiB"
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	const suffixes = "KMGTPE"
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit && exp < len(suffixes)-1; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), suffixes[exp])
+}
+
+// humanBytesAny is humanBytes for a value of any int64-like type,
+// including the named Bytes type stored via AppendDataAny, so it can be
+// used directly as a template func
+func humanBytesAny(v interface{}) string {
+	switch n := v.(type) {
+	case Bytes:
+		return humanBytes(int64(n))
+	case int64:
+		return humanBytes(n)
+	case int:
+		return humanBytes(int64(n))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// humanCount renders n using a k/M/B/T/Q suffix once it is large enough to
+// benefit from one, e.g. humanCount(12400) == "12.4k"
+func humanCount(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d", n)
+	}
+
+	const suffixes = "kMBTQ"
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit && exp < len(suffixes)-1; v /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), suffixes[exp])
+}
+
+// humanCountAny is humanCount for a value of any int64-like type,
+// including the named Count type stored via AppendDataAny, so it can be
+// used directly as a template func
+func humanCountAny(v interface{}) string {
+	switch n := v.(type) {
+	case Count:
+		return humanCount(int64(n))
+	case int64:
+		return humanCount(n)
+	case int:
+		return humanCount(int64(n))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// humanDuration renders d rounded to the largest two relevant units,
+// e.g. humanDuration(4*time.Hour + 12*time.Minute + 9*time.Second) == "4h12m"
+func humanDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.String()
+	}
+
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return d.String()
+	case d < time.Hour:
+		d = d.Round(time.Second)
+	default:
+		d = d.Round(time.Minute)
+	}
+
+	s := d.String()
+	// time.Duration.String() always includes seconds (e.g. "4h12m9s");
+	// trim them once we've rounded to minute precision for readability
+	if d >= time.Hour {
+		if i := strings.LastIndexByte(s, 'm'); i >= 0 {
+			s = s[:i+1]
+		}
+	}
+	return s
+}
+
+// humanTime renders t as a relative age (e.g. "4h12m ago"), the way
+// kubectl's AGE column does
+func humanTime(t time.Time) string {
+	return humanDuration(time.Since(t)) + " ago"
+}
@@ -0,0 +1,76 @@
+package utility
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+func TestHumanBytes(t *testing.T) {
+	cases := map[int64]string{
+		512:        "512 B",
+		1363148:    "1.3 MiB",
+		1073741824: "1.0 GiB",
+	}
+	for n, want := range cases {
+		if got := humanBytes(n); got != want {
+			t.Errorf("humanBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestHumanBytesDoesNotPanicOnHugeValues(t *testing.T) {
+	// 1<<62 bytes is 4 EiB, the "KMGTPE" table's last entry (E,
+	// exponent 5).
+	got := humanBytes(1 << 62)
+	if got == "" {
+		t.Fatal("humanBytes should still render something for very large values")
+	}
+}
+
+func TestHumanCountDoesNotPanicOnHugeValues(t *testing.T) {
+	// Past the old 3-entry "kMB" suffix table; used to panic with
+	// "index out of range [3] with length 3"
+	got := humanCount(2_000_000_000_000)
+	if got == "" {
+		t.Fatal("humanCount should still render something for very large values")
+	}
+}
+
+func TestHumanCount(t *testing.T) {
+	cases := map[int64]string{
+		42:    "42",
+		12400: "12.4k",
+	}
+	for n, want := range cases {
+		if got := humanCount(n); got != want {
+			t.Errorf("humanCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+// TestTemplateFuncsAcceptAppendDataAnyTypes reproduces the chunk0-6
+// workflow - AppendDataAny("Size", Bytes(n)) followed by
+// "{{humanBytes .Size}}" - which used to fail at execution with
+// "wrong type for value; expected int64; got utility.Bytes".
+func TestTemplateFuncsAcceptAppendDataAnyTypes(t *testing.T) {
+	tmpl, err := template.New("t").Funcs(templateFuncs).Parse("{{humanBytes .Size}} {{humanCount .Hits}}")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	data := map[string]interface{}{
+		"Size": Bytes(1363148),
+		"Hits": Count(12400),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	want := "1.3 MiB 12.4k"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
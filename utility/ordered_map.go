@@ -0,0 +1,55 @@
+package utility
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// orderedMap is a map[string]interface{} that marshals to JSON preserving
+// the order in which keys were inserted, instead of Go's randomized map
+// iteration order. This keeps `-o json` output stable across runs, which
+// matters for diffs, golden-file tests and piping through `jq`.
+type orderedMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{Values: map[string]interface{}{}}
+}
+
+// Set records value under key, preserving first-seen insertion order
+func (m *orderedMap) Set(key string, value interface{}) {
+	if _, found := m.Values[key]; !found {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+}
+
+// MarshalJSON implements json.Marshaler, writing keys in insertion order
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, k := range m.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		keyJSON, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(m.Values[k])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(valueJSON)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -3,6 +3,7 @@
 package utility
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,8 +11,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 
-	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/util/jsonpath"
 )
 
 type byLen []string
@@ -39,11 +42,37 @@ func (a byLen) Swap(i, j int) {
 //   ow.WriteCustomOutput(outputFields)
 //   ow.WriteKeyValues()
 //   ow.WriteTable()
+//
+// A single entry point, WriteOutput(format), is also available and
+// dispatches to the right writer for the value of a command's -o/--output
+// flag (e.g. "json", "yaml", "wide", "jsonpath=...", "go-template=...").
 type OutputWriter struct {
 	Keys       []string
 	Labels     []string
 	Values     [][]string
 	TempValues []string
+	// RawValues holds, for each row, the original value passed to
+	// AppendData/AppendDataAny, parallel to Values. Structured writers
+	// (JSON, YAML, jsonpath, go-template) use this so numbers, bools and
+	// nested structures survive instead of being flattened to strings.
+	RawValues [][]interface{}
+	tempRaw   []interface{}
+	// Tags holds, for each column in Keys, the set of tags it was
+	// appended with (see AppendDataWithTag). A column tagged "wide" is
+	// only shown in table and CSV output when Wide is true; the other
+	// writers always include it.
+	Tags [][]string
+	// Wide controls whether columns tagged "wide" are included in table
+	// and CSV output, as selected by the `-o wide` format.
+	Wide bool
+	// NoTTY forces the non-interactive watch-mode fallback (full
+	// snapshots instead of in-place redraw) even when stdout is a TTY,
+	// as selected by `--no-tty`.
+	NoTTY bool
+	// WatchOnly, when set, skips the initial full dump in BeginWatch so
+	// only subsequent UpdateRows frames are shown.
+	WatchOnly bool
+	watch     *watchState
 }
 
 // NewOutputWriter builds a new OutputWriter
@@ -69,16 +98,52 @@ func NewOutputWriterWithMap(data map[string]string) *OutputWriter {
 func (ow *OutputWriter) StartLine() {
 	ow.finishExistingLine()
 	ow.TempValues = make([]string, len(ow.Keys))
+	ow.tempRaw = make([]interface{}, len(ow.Keys))
 }
 
 func (ow *OutputWriter) finishExistingLine() {
 	if len(ow.TempValues) > 0 {
 		ow.Values = append(ow.Values, ow.TempValues)
+		ow.RawValues = append(ow.RawValues, ow.tempRaw)
+		// Clear so a repeated call (WriteTable after SortBy/Paginate/etc,
+		// or simply calling two Write* methods back to back) doesn't
+		// re-commit the same row again.
+		ow.TempValues = nil
+		ow.tempRaw = nil
 	}
 }
 
 // AppendDataWithLabel adds a line of data to the output writer
 func (ow *OutputWriter) AppendDataWithLabel(key, value, label string) {
+	ow.AppendDataWithTag(key, value, label)
+}
+
+// AppendData adds a line of data to the output writer
+func (ow *OutputWriter) AppendData(key, value string) {
+	ow.AppendDataWithLabel(key, value, key)
+}
+
+// AppendDataAny adds a line of data to the output writer without forcing
+// value to a string first, so structured formats (JSON, YAML, jsonpath,
+// go-template) keep its real type - numbers stay numbers, bools stay
+// bools, time.Time serializes as RFC3339, and slices/maps serialize as
+// nested structures. Table/key-value output renders it via the Formatter
+// registered for its type (see RegisterFormatter), falling back to
+// fmt.Sprint.
+func (ow *OutputWriter) AppendDataAny(key string, value interface{}) {
+	ow.appendAny(key, value, key)
+}
+
+// AppendDataWithTag adds a line of data to the output writer and tags the
+// column with zero or more tags. The only tag currently understood by the
+// built-in writers is "wide", which hides the column from table and CSV
+// output unless Wide is set (i.e. the user asked for `-o wide`), so commands can surface extra detail
+// without cluttering the default table.
+func (ow *OutputWriter) AppendDataWithTag(key, value, label string, tags ...string) {
+	ow.appendAny(key, value, label, tags...)
+}
+
+func (ow *OutputWriter) appendAny(key string, value interface{}, label string, tags ...string) {
 	found := -1
 	for i, v := range ow.Keys {
 		if v == key {
@@ -86,31 +151,87 @@ func (ow *OutputWriter) AppendDataWithLabel(key, value, label string) {
 		}
 	}
 
+	display := formatValue(value)
+
 	if found == -1 {
 		ow.Keys = append(ow.Keys, key)
 		ow.Labels = append(ow.Labels, label)
-		ow.TempValues = append(ow.TempValues, value)
+		ow.TempValues = append(ow.TempValues, display)
+		ow.tempRaw = append(ow.tempRaw, value)
+		ow.Tags = append(ow.Tags, tags)
 	} else {
-		ow.TempValues[found] = value
+		ow.TempValues[found] = display
+		ow.tempRaw[found] = value
+		ow.Tags[found] = tags
 	}
 }
 
-// AppendData adds a line of data to the output writer
-func (ow *OutputWriter) AppendData(key, value string) {
-	ow.AppendDataWithLabel(key, value, key)
+// isWideColumn reports whether the column at index i was appended with a
+// "wide" tag
+func (ow *OutputWriter) isWideColumn(i int) bool {
+	for _, t := range ow.Tags[i] {
+		if t == "wide" {
+			return true
+		}
+	}
+	return false
 }
 
-// WriteSingleObjectJSON writes the JSON for a single object to STDOUT
-func (ow *OutputWriter) WriteSingleObjectJSON() {
-	ow.finishExistingLine()
+// visibleColumns returns the indexes of the columns that should be shown,
+// honouring Wide
+func (ow *OutputWriter) visibleColumns() []int {
+	cols := make([]int, 0, len(ow.Keys))
+	for i := range ow.Keys {
+		if ow.Wide || !ow.isWideColumn(i) {
+			cols = append(cols, i)
+		}
+	}
+	return cols
+}
+
+// rowMap returns row i as a map keyed by column key, with each value in
+// its original type (see AppendDataAny)
+func (ow *OutputWriter) rowMap(row int) map[string]interface{} {
+	data := map[string]interface{}{}
+	for i, k := range ow.Keys {
+		data[k] = ow.RawValues[row][i]
+	}
+	return data
+}
 
-	data := map[string]string{}
+// allRowMaps returns every row as a map keyed by column key
+func (ow *OutputWriter) allRowMaps() []map[string]interface{} {
+	data := make([]map[string]interface{}, len(ow.Values))
+	for i := range ow.Values {
+		data[i] = ow.rowMap(i)
+	}
+	return data
+}
 
+// orderedRow returns row i as an orderedMap, preserving the order in
+// which AppendData calls added each column
+func (ow *OutputWriter) orderedRow(row int) *orderedMap {
+	m := newOrderedMap()
 	for i, k := range ow.Keys {
-		data[k] = ow.Values[0][i]
+		m.Set(k, ow.RawValues[row][i])
 	}
+	return m
+}
+
+// orderedRows returns every row as an orderedMap
+func (ow *OutputWriter) orderedRows() []*orderedMap {
+	data := make([]*orderedMap, len(ow.Values))
+	for i := range ow.Values {
+		data[i] = ow.orderedRow(i)
+	}
+	return data
+}
 
-	jsonString, err := json.Marshal(data)
+// WriteSingleObjectJSON writes the JSON for a single object to STDOUT
+func (ow *OutputWriter) WriteSingleObjectJSON() {
+	ow.finishExistingLine()
+
+	jsonString, err := json.Marshal(ow.orderedRow(0))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
@@ -123,16 +244,35 @@ func (ow *OutputWriter) WriteSingleObjectJSON() {
 func (ow *OutputWriter) WriteMultipleObjectsJSON() {
 	ow.finishExistingLine()
 
-	data := make([]map[string]string, len(ow.Values))
-	for i, row := range ow.Values {
-		dataRow := map[string]string{}
-		for col, k := range ow.Keys {
-			dataRow[k] = row[col]
-		}
-		data[i] = dataRow
+	jsonString, err := json.Marshal(ow.orderedRows())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	fmt.Println(string(jsonString))
+}
+
+// WriteSingleObjectJSONIndent writes the indented, human-friendly JSON for
+// a single object to STDOUT
+func (ow *OutputWriter) WriteSingleObjectJSONIndent() {
+	ow.finishExistingLine()
+
+	jsonString, err := json.MarshalIndent(ow.orderedRow(0), "", "  ")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
 	}
 
-	jsonString, err := json.Marshal(data)
+	fmt.Println(string(jsonString))
+}
+
+// WriteMultipleObjectsJSONIndent writes the indented, human-friendly JSON
+// for multiple objects to STDOUT
+func (ow *OutputWriter) WriteMultipleObjectsJSONIndent() {
+	ow.finishExistingLine()
+
+	jsonString, err := json.MarshalIndent(ow.orderedRows(), "", "  ")
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
@@ -164,69 +304,192 @@ func (ow *OutputWriter) WriteKeyValues() {
 // in tabular format
 func (ow *OutputWriter) WriteTable() {
 	ow.finishExistingLine()
+	fmt.Print(ow.renderTableString())
+}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	if len(ow.Keys) > 0 {
-		table.SetHeader(ow.Labels)
-		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-		table.SetAutoWrapText(false)
-		table.SetAutoFormatHeaders(false)
-	} else {
-		table.SetBorder(false)
+// WriteYAML writes the YAML for a single object to STDOUT
+func (ow *OutputWriter) WriteYAML() error {
+	ow.finishExistingLine()
+
+	yamlBytes, err := yaml.Marshal(ow.rowMap(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(string(yamlBytes))
+	return nil
+}
+
+// WriteYAMLMultiple writes the YAML for multiple objects to STDOUT
+func (ow *OutputWriter) WriteYAMLMultiple() error {
+	ow.finishExistingLine()
+
+	yamlBytes, err := yaml.Marshal(ow.allRowMaps())
+	if err != nil {
+		return err
 	}
 
-	table.AppendBulk(ow.Values)
-	table.Render()
+	fmt.Print(string(yamlBytes))
+	return nil
 }
 
-// Replace the nth occurrence of old in s by new.
-func replaceNth(s, old, new string, n int) string {
-	i := 0
-	for m := 1; m <= n; m++ {
-		x := strings.Index(s[i:], old)
-		if x < 0 {
-			break
+// WriteCSV writes the stored rows to STDOUT as CSV, using the column
+// labels as the header row
+func (ow *OutputWriter) WriteCSV() error {
+	ow.finishExistingLine()
+
+	cols := ow.visibleColumns()
+	w := csv.NewWriter(os.Stdout)
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = ow.Labels[c]
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range ow.Values {
+		record := make([]string, len(cols))
+		for i, c := range cols {
+			record[i] = row[c]
+		}
+		if err := w.Write(record); err != nil {
+			return err
 		}
-		i += x
-		if m == n {
-			return s[:i] + new + s[i+len(old):]
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// WriteJSONPath renders the stored data through a kubectl-style JSONPath
+// expression, e.g. "{.ID}" for a single row or
+// "{range .items[*]}{.ID}{\"\\n\"}{end}" across multiple rows - multiple
+// rows are wrapped as {"items": [...]} so the documented kubectl ".items"
+// syntax works against them.
+func (ow *OutputWriter) WriteJSONPath(expr string) error {
+	ow.finishExistingLine()
+
+	jp := jsonpath.New("output")
+	if err := jp.Parse(expr); err != nil {
+		return err
+	}
+
+	var data interface{}
+	if len(ow.Values) > 1 {
+		data = map[string]interface{}{"items": ow.allRowMaps()}
+	} else {
+		data = ow.rowMap(0)
+	}
+
+	return jp.Execute(os.Stdout, data)
+}
+
+// WriteGoTemplate renders the stored data through a text/template
+// template, executed once per row
+func (ow *OutputWriter) WriteGoTemplate(tmpl string) error {
+	ow.finishExistingLine()
+
+	t, err := template.New("output").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range ow.allRowMaps() {
+		if err := t.Execute(os.Stdout, row); err != nil {
+			return err
 		}
-		i += len(old)
 	}
-	return s
+	return nil
 }
 
-// WriteCustomOutput prints one or multiple objects using custom formatting
-func (ow *OutputWriter) WriteCustomOutput(fields string) {
+// WriteOutput parses an -o/--output format string and dispatches to the
+// matching writer. Supported formats are "" / "human" (table), "wide",
+// "json", "yaml", "csv", "jsonpath=<expr>", "go-template=<tmpl>" and
+// "custom=<fields>" (the legacy WriteCustomOutput field string).
+func (ow *OutputWriter) WriteOutput(format string) error {
 	ow.finishExistingLine()
-	defaultKeys := make([]string, len(ow.Keys))
-	copy(defaultKeys, ow.Keys)
-	sort.Sort(byLen(ow.Keys))
-
-	//build my custom map
-	customMap := make(map[string]string)
-	for index, key := range defaultKeys {
-		customMap[key] = ow.Values[0][index]
-	}
-
-	for range ow.Values {
-		output := fields
-		for key, name := range ow.Keys {
-			var re = regexp.MustCompile(fmt.Sprintf(`%s`, name))
-			if len(re.FindStringIndex(output)) > 0 {
-				output = replaceNth(output, name, fmt.Sprintf("$%v$", key), 1)
-			}
+
+	switch {
+	case format == "" || format == "human":
+		ow.WriteTable()
+	case format == "wide":
+		ow.Wide = true
+		ow.WriteTable()
+	case format == "json":
+		if len(ow.Values) > 1 {
+			ow.WriteMultipleObjectsJSON()
+		} else {
+			ow.WriteSingleObjectJSON()
+		}
+	case format == "yaml":
+		if len(ow.Values) > 1 {
+			return ow.WriteYAMLMultiple()
 		}
+		return ow.WriteYAML()
+	case format == "csv":
+		return ow.WriteCSV()
+	case strings.HasPrefix(format, "jsonpath="):
+		return ow.WriteJSONPath(strings.TrimPrefix(format, "jsonpath="))
+	case strings.HasPrefix(format, "go-template="):
+		return ow.WriteGoTemplate(strings.TrimPrefix(format, "go-template="))
+	case strings.HasPrefix(format, "custom="):
+		return ow.WriteCustomOutput(strings.TrimPrefix(format, "custom="))
+	default:
+		return ow.WriteCustomOutput(format)
+	}
+	return nil
+}
+
+// legacyFieldsToTemplate translates the original WriteCustomOutput syntax
+// - bare column names such as "ID\tName" - into a text/template string
+// such as "{{.ID}}\t{{.Name}}", for backwards compatibility with format
+// strings written before templates were supported. A fields string that
+// already looks like a template (contains "{{") is passed through
+// unchanged. Keys are substituted longest-first and on word boundaries so
+// that a key which is a prefix of another key, or which happens to appear
+// inside a literal value, isn't corrupted - the bug the old regexp/`$N$`
+// based implementation had.
+func legacyFieldsToTemplate(fields string, keys []string) string {
+	if strings.Contains(fields, "{{") {
+		return fields
+	}
+
+	sortedKeys := make([]string, len(keys))
+	copy(sortedKeys, keys)
+	sort.Sort(byLen(sortedKeys))
+
+	for _, key := range sortedKeys {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(key) + `\b`)
+		fields = re.ReplaceAllString(fields, fmt.Sprintf("{{.%s}}", key))
+	}
+
+	return fields
+}
+
+// WriteCustomOutput prints every stored row using a custom format string:
+// either a text/template such as "{{.ID}}\t{{humanBytes .Size}}", or the
+// legacy bare-identifier syntax such as "ID\tName" (translated to a
+// template by legacyFieldsToTemplate).
+func (ow *OutputWriter) WriteCustomOutput(fields string) error {
+	ow.finishExistingLine()
+
+	fields = strings.Replace(fields, "\\t", "\t", -1)
+	fields = strings.Replace(fields, "\\n", "\n", -1)
+
+	t, err := template.New("custom").Funcs(templateFuncs).Parse(legacyFieldsToTemplate(fields, ow.Keys))
+	if err != nil {
+		return err
+	}
 
-		for index, name := range ow.Keys {
-			if strings.Contains(output, fmt.Sprintf("$%v$", index)) {
-				output = strings.Replace(output, fmt.Sprintf("$%v$", index), customMap[name], 1)
-			}
+	for _, row := range ow.allRowMaps() {
+		if err := t.Execute(os.Stdout, row); err != nil {
+			return err
 		}
-		output = strings.Replace(output, "\\t", "\t", -1)
-		output = strings.Replace(output, "\\n", "\n", -1)
-		fmt.Println(output)
+		fmt.Println()
 	}
+	return nil
 }
 
 // WriteSubheader writes a centred heading line in to output
@@ -0,0 +1,374 @@
+package utility
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columnIndex returns the index of key among ow.Keys, falling back to
+// ow.Labels, or -1 if it matches neither
+func (ow *OutputWriter) columnIndex(key string) int {
+	for i, k := range ow.Keys {
+		if k == key {
+			return i
+		}
+	}
+	for i, l := range ow.Labels {
+		if l == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// reorder replaces the stored rows with ow.Values[order[i]] for each i,
+// keeping Values and RawValues in lockstep. It underlies SortBy,
+// FilterFieldSelector, FilterLabelSelector and Paginate.
+func (ow *OutputWriter) reorder(order []int) {
+	values := make([][]string, len(order))
+	raw := make([][]interface{}, len(order))
+	for newIdx, oldIdx := range order {
+		values[newIdx] = ow.Values[oldIdx]
+		raw[newIdx] = ow.RawValues[oldIdx]
+	}
+	ow.Values = values
+	ow.RawValues = raw
+}
+
+// compareRaw orders two raw AppendDataAny values - Bytes, Count,
+// time.Duration, time.Time, int, int64 and float64 - when they
+// share a naturally ordered type, reporting false for ok when it can't
+// (e.g. mismatched types, or types with no natural order), so the caller
+// can fall back to comparing the rendered display string.
+func compareRaw(a, b interface{}) (less bool, ok bool) {
+	switch av := a.(type) {
+	case Bytes:
+		if bv, match := b.(Bytes); match {
+			return av < bv, true
+		}
+	case Count:
+		if bv, match := b.(Count); match {
+			return av < bv, true
+		}
+	case time.Duration:
+		if bv, match := b.(time.Duration); match {
+			return av < bv, true
+		}
+	case time.Time:
+		if bv, match := b.(time.Time); match {
+			return av.Before(bv), true
+		}
+	case int:
+		if bv, match := b.(int); match {
+			return av < bv, true
+		}
+	case int64:
+		if bv, match := b.(int64); match {
+			return av < bv, true
+		}
+	case float64:
+		if bv, match := b.(float64); match {
+			return av < bv, true
+		}
+	}
+	return false, false
+}
+
+// SortBy orders the stored rows by the value of column (matched against
+// Keys, then Labels), descending when desc is true. Rows appended via
+// AppendDataAny with a naturally ordered raw type (Bytes, Count,
+// time.Duration, time.Time, or a plain number) are compared by that raw
+// value, so a Formatter-humanized display string (e.g. "1.4 GiB") doesn't
+// throw off the ordering. Otherwise values that parse as numbers on both
+// sides are compared numerically, and everything else is compared
+// lexically. Wires up `--sort-by`.
+func (ow *OutputWriter) SortBy(column string, desc bool) error {
+	ow.finishExistingLine()
+
+	idx := ow.columnIndex(column)
+	if idx == -1 {
+		return fmt.Errorf("no such column: %s", column)
+	}
+
+	order := make([]int, len(ow.Values))
+	for i := range order {
+		order[i] = i
+	}
+
+	less := func(i, j int) bool {
+		if lt, ok := compareRaw(ow.RawValues[order[i]][idx], ow.RawValues[order[j]][idx]); ok {
+			return lt
+		}
+
+		a, b := ow.Values[order[i]][idx], ow.Values[order[j]][idx]
+		if af, aerr := strconv.ParseFloat(a, 64); aerr == nil {
+			if bf, berr := strconv.ParseFloat(b, 64); berr == nil {
+				return af < bf
+			}
+		}
+		return a < b
+	}
+	if desc {
+		asc := less
+		less = func(i, j int) bool { return asc(j, i) }
+	}
+	sort.SliceStable(order, less)
+
+	ow.reorder(order)
+	return nil
+}
+
+// fieldCondition is a single "column[=|==|!=]value" term of a field
+// selector
+type fieldCondition struct {
+	column string
+	negate bool
+	value  string
+}
+
+func parseFieldSelector(expr string) ([]fieldCondition, error) {
+	var conds []fieldCondition
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		negate := false
+		sep := "="
+		switch {
+		case strings.Contains(term, "!="):
+			negate, sep = true, "!="
+		case strings.Contains(term, "=="):
+			sep = "=="
+		case strings.Contains(term, "="):
+			sep = "="
+		default:
+			return nil, fmt.Errorf("invalid field selector term: %q", term)
+		}
+
+		parts := strings.SplitN(term, sep, 2)
+		conds = append(conds, fieldCondition{
+			column: strings.TrimSpace(parts[0]),
+			negate: negate,
+			value:  strings.TrimSpace(parts[1]),
+		})
+	}
+	return conds, nil
+}
+
+// FilterFieldSelector keeps only the rows matching a kubectl-style field
+// selector expression, e.g. "status=ACTIVE,region!=LON1". Supports "=",
+// "==" and "!=", ANDed together across commas. Wires up
+// `--field-selector`.
+func (ow *OutputWriter) FilterFieldSelector(expr string) error {
+	ow.finishExistingLine()
+
+	conds, err := parseFieldSelector(expr)
+	if err != nil {
+		return err
+	}
+
+	condIdx := make([]int, len(conds))
+	for i, c := range conds {
+		idx := ow.columnIndex(c.column)
+		if idx == -1 {
+			return fmt.Errorf("no such column: %s", c.column)
+		}
+		condIdx[i] = idx
+	}
+
+	var order []int
+	for i, row := range ow.Values {
+		match := true
+		for j, c := range conds {
+			equal := row[condIdx[j]] == c.value
+			if equal == c.negate {
+				match = false
+				break
+			}
+		}
+		if match {
+			order = append(order, i)
+		}
+	}
+
+	ow.reorder(order)
+	return nil
+}
+
+// labelRequirement is a single term of a label selector, evaluated
+// against a row's parsed Labels column
+type labelRequirement struct {
+	key    string
+	op     string // "exists", "notexists", "in", "notin"
+	values []string
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	v, found := labels[r.key]
+	switch r.op {
+	case "exists":
+		return found
+	case "notexists":
+		return !found
+	case "in":
+		return found && containsString(r.values, v)
+	case "notin":
+		return !found || !containsString(r.values, v)
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLabels turns a "k1=v1,k2=v2" string - as appended via
+// AppendData("Labels", ...) - into a lookup map. A bare key with no "="
+// is recorded with an empty value, so "exists" checks still work.
+func parseLabels(s string) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else {
+			labels[kv[0]] = ""
+		}
+	}
+	return labels
+}
+
+func parseSet(s string) []string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "(")
+	s = strings.TrimSuffix(s, ")")
+
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		out = append(out, strings.TrimSpace(v))
+	}
+	return out
+}
+
+func parseLabelSelector(expr string) ([]labelRequirement, error) {
+	// Split on commas outside parentheses, so "k in (a,b)" stays one term
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, expr[start:])
+
+	var reqs []labelRequirement
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(term, "!"):
+			reqs = append(reqs, labelRequirement{key: strings.TrimPrefix(term, "!"), op: "notexists"})
+		case strings.Contains(term, " notin "):
+			parts := strings.SplitN(term, " notin ", 2)
+			reqs = append(reqs, labelRequirement{key: strings.TrimSpace(parts[0]), op: "notin", values: parseSet(parts[1])})
+		case strings.Contains(term, " in "):
+			parts := strings.SplitN(term, " in ", 2)
+			reqs = append(reqs, labelRequirement{key: strings.TrimSpace(parts[0]), op: "in", values: parseSet(parts[1])})
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			reqs = append(reqs, labelRequirement{key: strings.TrimSpace(parts[0]), op: "notin", values: []string{strings.TrimSpace(parts[1])}})
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			reqs = append(reqs, labelRequirement{key: strings.TrimSpace(parts[0]), op: "in", values: []string{strings.TrimSpace(parts[1])}})
+		default:
+			reqs = append(reqs, labelRequirement{key: term, op: "exists"})
+		}
+	}
+	return reqs, nil
+}
+
+// FilterLabelSelector keeps only the rows matching a kubectl-style label
+// selector expression (e.g. "environment in (prod,staging),!deprecated")
+// evaluated against the row's "Labels" column, which is expected to hold
+// a comma-separated "key=value" list (as appended via
+// AppendData("Labels", ...)). Wires up `--selector`/`-l`.
+func (ow *OutputWriter) FilterLabelSelector(expr string) error {
+	ow.finishExistingLine()
+
+	idx := ow.columnIndex("Labels")
+	if idx == -1 {
+		return fmt.Errorf("no Labels column to filter on")
+	}
+
+	reqs, err := parseLabelSelector(expr)
+	if err != nil {
+		return err
+	}
+
+	var order []int
+	for i, row := range ow.Values {
+		labels := parseLabels(row[idx])
+		match := true
+		for _, r := range reqs {
+			if !r.matches(labels) {
+				match = false
+				break
+			}
+		}
+		if match {
+			order = append(order, i)
+		}
+	}
+
+	ow.reorder(order)
+	return nil
+}
+
+// Paginate keeps only rows[offset : offset+limit] (a non-positive limit
+// means no upper bound), wiring up `--offset`/`--limit`.
+func (ow *OutputWriter) Paginate(offset, limit int) {
+	ow.finishExistingLine()
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(ow.Values) {
+		offset = len(ow.Values)
+	}
+
+	end := len(ow.Values)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	order := make([]int, 0, end-offset)
+	for i := offset; i < end; i++ {
+		order = append(order, i)
+	}
+	ow.reorder(order)
+}
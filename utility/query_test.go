@@ -0,0 +1,145 @@
+package utility
+
+import "testing"
+
+func newQueryTestWriter(rows [][2]string) *OutputWriter {
+	ow := NewOutputWriter()
+	for _, row := range rows {
+		ow.StartLine()
+		ow.AppendData("ID", row[0])
+		ow.AppendData("Labels", row[1])
+	}
+	return ow
+}
+
+func idColumn(ow *OutputWriter) []string {
+	ids := make([]string, len(ow.Values))
+	for i, row := range ow.Values {
+		ids[i] = row[0]
+	}
+	return ids
+}
+
+func TestSortByNumericStrings(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{{"3", ""}, {"1", ""}, {"2", ""}})
+
+	if err := ow.SortBy("ID", false); err != nil {
+		t.Fatalf("SortBy: %v", err)
+	}
+
+	got := idColumn(ow)
+	want := []string{"1", "2", "3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("SortBy ascending = %v, want %v", got, want)
+		}
+	}
+
+	if err := ow.SortBy("ID", true); err != nil {
+		t.Fatalf("SortBy desc: %v", err)
+	}
+	got = idColumn(ow)
+	want = []string{"3", "2", "1"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("SortBy descending = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSortByUnknownColumn(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{{"1", ""}})
+
+	if err := ow.SortBy("Nope", false); err == nil {
+		t.Fatal("SortBy with an unknown column should return an error")
+	}
+}
+
+func TestSortByUsesRawValueNotHumanizedString(t *testing.T) {
+	ow := NewOutputWriter()
+
+	ow.StartLine()
+	ow.AppendDataAny("Size", Bytes(500*1024*1024)) // "500.0 MiB"
+	ow.StartLine()
+	ow.AppendDataAny("Size", Bytes(1363148800)) // "1.3 GiB" - lexically sorts before "500.0 MiB"
+
+	if err := ow.SortBy("Size", false); err != nil {
+		t.Fatalf("SortBy: %v", err)
+	}
+
+	got := []string{ow.Values[0][0], ow.Values[1][0]}
+	want := []string{"500.0 MiB", "1.3 GiB"}
+	if got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("SortBy on Bytes column = %v, want %v (numeric, not lexical)", got, want)
+	}
+}
+
+func TestFilterFieldSelector(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{{"1", ""}, {"2", ""}, {"3", ""}})
+
+	if err := ow.FilterFieldSelector("ID!=2"); err != nil {
+		t.Fatalf("FilterFieldSelector: %v", err)
+	}
+
+	got := idColumn(ow)
+	want := []string{"1", "3"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("FilterFieldSelector = %v, want %v", got, want)
+	}
+}
+
+func TestFilterFieldSelectorUnknownColumnErrors(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{{"1", ""}, {"2", ""}})
+
+	err := ow.FilterFieldSelector("statys=1")
+	if err == nil {
+		t.Fatal("FilterFieldSelector with an unknown column should return an error, not silently filter everything out")
+	}
+}
+
+func TestFilterLabelSelector(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{
+		{"1", "environment=prod,tier=frontend"},
+		{"2", "environment=staging,tier=backend"},
+		{"3", "environment=prod,tier=backend,deprecated=true"},
+	})
+
+	if err := ow.FilterLabelSelector("environment=prod,!deprecated"); err != nil {
+		t.Fatalf("FilterLabelSelector: %v", err)
+	}
+
+	got := idColumn(ow)
+	if len(got) != 1 || got[0] != "1" {
+		t.Fatalf("FilterLabelSelector = %v, want [1]", got)
+	}
+}
+
+func TestFilterLabelSelectorIn(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{
+		{"1", "tier=frontend"},
+		{"2", "tier=backend"},
+		{"3", "tier=database"},
+	})
+
+	if err := ow.FilterLabelSelector("tier in (frontend,backend)"); err != nil {
+		t.Fatalf("FilterLabelSelector: %v", err)
+	}
+
+	got := idColumn(ow)
+	want := []string{"1", "2"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("FilterLabelSelector in = %v, want %v", got, want)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	ow := newQueryTestWriter([][2]string{{"1", ""}, {"2", ""}, {"3", ""}, {"4", ""}})
+
+	ow.Paginate(1, 2)
+
+	got := idColumn(ow)
+	want := []string{"2", "3"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Paginate(1, 2) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package utility
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/term"
+)
+
+// watchState tracks the in-progress watch frame started by BeginWatch
+type watchState struct {
+	tty       bool
+	interval  time.Duration
+	lastLines int
+}
+
+// BeginWatch starts watch mode for list commands run with `--watch`/`-w`:
+// subsequent calls to UpdateRows re-render the table in place instead of
+// appending a new one. On a TTY this uses ANSI cursor control to clear the
+// previous frame; when stdout isn't a TTY (or NoTTY is set) it falls back
+// to appending full snapshots, since there is no "in place" on a pipe or
+// log file. Unless WatchOnly is set, the current data is rendered once
+// immediately as the initial full dump.
+func (ow *OutputWriter) BeginWatch(interval time.Duration) {
+	ow.watch = &watchState{
+		tty:      !ow.NoTTY && term.IsTerminal(int(os.Stdout.Fd())),
+		interval: interval,
+	}
+
+	if !ow.WatchOnly {
+		ow.finishExistingLine()
+		frame := ow.renderTableString()
+		ow.watch.lastLines = strings.Count(frame, "\n")
+		fmt.Print(frame)
+	}
+}
+
+// UpdateRows redraws the table with a new snapshot of rows: on a TTY it
+// clears the previous frame first, otherwise it appends the new snapshot
+// so output stays readable when piped or redirected to a file. Call
+// BeginWatch first.
+func (ow *OutputWriter) UpdateRows(rows [][]string) {
+	if ow.watch == nil {
+		ow.watch = &watchState{}
+	}
+
+	ow.Values = rows
+	ow.RawValues = make([][]interface{}, len(rows))
+	for i, row := range rows {
+		raw := make([]interface{}, len(row))
+		for j, v := range row {
+			raw[j] = v
+		}
+		ow.RawValues[i] = raw
+	}
+
+	frame := ow.renderTableString()
+
+	if ow.watch.tty {
+		clearLines(ow.watch.lastLines)
+	}
+	ow.watch.lastLines = strings.Count(frame, "\n")
+
+	fmt.Print(frame)
+}
+
+// EndWatch stops watch mode, leaving the last rendered frame in place
+func (ow *OutputWriter) EndWatch() {
+	ow.watch = nil
+}
+
+// renderTableString renders the current rows as a table and returns it as
+// a string instead of writing it to STDOUT, so callers can measure it
+// (e.g. to know how many lines to clear for the next watch frame)
+func (ow *OutputWriter) renderTableString() string {
+	var buf bytes.Buffer
+
+	table := tablewriter.NewWriter(&buf)
+	cols := ow.visibleColumns()
+	if len(ow.Keys) > 0 {
+		labels := make([]string, len(cols))
+		for i, c := range cols {
+			labels[i] = ow.Labels[c]
+		}
+		table.SetHeader(labels)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAutoWrapText(false)
+		table.SetAutoFormatHeaders(false)
+	} else {
+		table.SetBorder(false)
+	}
+
+	rows := make([][]string, len(ow.Values))
+	for i, row := range ow.Values {
+		filtered := make([]string, len(cols))
+		for j, c := range cols {
+			filtered[j] = row[c]
+		}
+		rows[i] = filtered
+	}
+
+	table.AppendBulk(rows)
+	table.Render()
+	return buf.String()
+}
+
+// clearLines moves the cursor up n lines and clears from there to the end
+// of the screen, erasing the previously rendered watch frame
+func clearLines(n int) {
+	if n <= 0 {
+		return
+	}
+	fmt.Printf("\x1b[%dA\x1b[J", n)
+}